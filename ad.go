@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	flag.BoolVar(&rawOutput, "raw", false, "bypass the userAccountControl/objectSid/objectGUID decoders and show the underlying value")
+}
+
+var rawOutput bool
+
+// uacFlags is the userAccountControl bitmask table from MS-ADTS
+// section 2.2.16, in ascending bit order.
+var uacFlags = []struct {
+	bit  uint32
+	name string
+}{
+	{0x00000001, "SCRIPT"},
+	{0x00000002, "ACCOUNTDISABLE"},
+	{0x00000008, "HOMEDIR_REQUIRED"},
+	{0x00000010, "LOCKOUT"},
+	{0x00000020, "PASSWD_NOTREQD"},
+	{0x00000040, "PASSWD_CANT_CHANGE"},
+	{0x00000080, "ENCRYPTED_TEXT_PWD_ALLOWED"},
+	{0x00000100, "TEMP_DUPLICATE_ACCOUNT"},
+	{0x00000200, "NORMAL_ACCOUNT"},
+	{0x00000800, "INTERDOMAIN_TRUST_ACCOUNT"},
+	{0x00001000, "WORKSTATION_TRUST_ACCOUNT"},
+	{0x00002000, "SERVER_TRUST_ACCOUNT"},
+	{0x00010000, "DONT_EXPIRE_PASSWORD"},
+	{0x00020000, "MNS_LOGON_ACCOUNT"},
+	{0x00040000, "SMARTCARD_REQUIRED"},
+	{0x00080000, "TRUSTED_FOR_DELEGATION"},
+	{0x00100000, "NOT_DELEGATED"},
+	{0x00200000, "USE_DES_KEY_ONLY"},
+	{0x00400000, "DONT_REQ_PREAUTH"},
+	{0x00800000, "PASSWORD_EXPIRED"},
+	{0x01000000, "TRUSTED_TO_AUTH_FOR_DELEGATION"},
+	{0x04000000, "PARTIAL_SECRETS_ACCOUNT"},
+}
+
+// displayUserAccountControlFn parses the userAccountControl int32
+// bitmask and renders it as the "|"-joined list of set flag names.
+func displayUserAccountControlFn(val string) (interface{}, string) {
+	mask, err := strconv.ParseUint(val, 10, 32)
+	if err != nil {
+		return nil, fmt.Sprintf("ERROR: '%s'", err)
+	}
+
+	var names []string
+	for _, flag := range uacFlags {
+		if uint32(mask)&flag.bit != 0 {
+			names = append(names, flag.name)
+		}
+	}
+	if len(names) == 0 {
+		return uint32(mask), "(none)"
+	}
+	return uint32(mask), strings.Join(names, "|")
+}
+
+// displaySIDFn parses a binary objectSid value (revision byte,
+// sub-authority count, a 6-byte big-endian identifier authority, then
+// that many little-endian uint32 sub-authorities) into its canonical
+// "S-1-<auth>-<sub1>-..." string form.
+func displaySIDFn(val string) (interface{}, string) {
+	buf := []byte(val)
+	if len(buf) < 8 {
+		return nil, fmt.Sprintf("ERROR: 'objectSid too short (%d bytes)'", len(buf))
+	}
+
+	revision := buf[0]
+	subAuthorityCount := int(buf[1])
+
+	var authority uint64
+	for _, b := range buf[2:8] {
+		authority = authority<<8 | uint64(b)
+	}
+
+	if len(buf) < 8+4*subAuthorityCount {
+		return nil, fmt.Sprintf("ERROR: 'objectSid truncated (%d bytes, want %d)'", len(buf), 8+4*subAuthorityCount)
+	}
+
+	sid := fmt.Sprintf("S-%d-%d", revision, authority)
+	for i := 0; i < subAuthorityCount; i++ {
+		offset := 8 + 4*i
+		sub := binary.LittleEndian.Uint32(buf[offset : offset+4])
+		sid += fmt.Sprintf("-%d", sub)
+	}
+	return sid, sid
+}
+
+// displayGUIDFn parses a binary objectGUID value (16 bytes: three
+// little-endian fields followed by two big-endian byte groups) into
+// the canonical "xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx" GUID string.
+func displayGUIDFn(val string) (interface{}, string) {
+	buf := []byte(val)
+	if len(buf) != 16 {
+		return nil, fmt.Sprintf("ERROR: 'objectGUID wrong length (%d bytes, want 16)'", len(buf))
+	}
+
+	guid := fmt.Sprintf("%08x-%04x-%04x-%02x%02x-%02x%02x%02x%02x%02x%02x",
+		binary.LittleEndian.Uint32(buf[0:4]),
+		binary.LittleEndian.Uint16(buf[4:6]),
+		binary.LittleEndian.Uint16(buf[6:8]),
+		buf[8], buf[9],
+		buf[10], buf[11], buf[12], buf[13], buf[14], buf[15])
+	return guid, guid
+}
@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+func TestDisplayUserAccountControlFn(t *testing.T) {
+	tests := []struct {
+		name string
+		val  string
+		raw  interface{}
+		str  string
+	}{
+		{"normal account", "512", uint32(512), "NORMAL_ACCOUNT"},
+		{"disabled normal account", "514", uint32(514), "ACCOUNTDISABLE|NORMAL_ACCOUNT"},
+		{"no flags set", "0", uint32(0), "(none)"},
+		{"not a number", "nope", nil, "ERROR: 'strconv.ParseUint: parsing \"nope\": invalid syntax'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, str := displayUserAccountControlFn(tt.val)
+			if raw != tt.raw {
+				t.Errorf("raw = %#v, want %#v", raw, tt.raw)
+			}
+			if str != tt.str {
+				t.Errorf("str = %q, want %q", str, tt.str)
+			}
+		})
+	}
+}
+
+func TestDisplaySIDFn(t *testing.T) {
+	// S-1-5-21-1-2
+	buf := []byte{
+		0x01,                               // revision
+		0x03,                               // sub-authority count
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x05, // identifier authority (big-endian)
+		0x15, 0x00, 0x00, 0x00, // sub-authority 1 (21, little-endian)
+		0x01, 0x00, 0x00, 0x00, // sub-authority 2 (1, little-endian)
+		0x02, 0x00, 0x00, 0x00, // sub-authority 3 (2, little-endian)
+	}
+	want := "S-1-5-21-1-2"
+
+	raw, str := displaySIDFn(string(buf))
+	if raw != want {
+		t.Errorf("raw = %#v, want %#v", raw, want)
+	}
+	if str != want {
+		t.Errorf("str = %q, want %q", str, want)
+	}
+
+	if _, str := displaySIDFn("short"); str == want {
+		t.Errorf("expected error string for truncated input, got %q", str)
+	}
+}
+
+func TestDisplayGUIDFn(t *testing.T) {
+	buf := []byte{
+		0x01, 0x02, 0x03, 0x04,
+		0x05, 0x06,
+		0x07, 0x08,
+		0x09, 0x0a,
+		0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+	}
+	want := "04030201-0605-0807-090a-0b0c0d0e0f10"
+
+	raw, str := displayGUIDFn(string(buf))
+	if raw != want {
+		t.Errorf("raw = %#v, want %#v", raw, want)
+	}
+	if str != want {
+		t.Errorf("str = %q, want %q", str, want)
+	}
+
+	if _, str := displayGUIDFn("too short"); str == want {
+		t.Errorf("expected error string for wrong-length input, got %q", str)
+	}
+}
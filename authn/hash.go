@@ -0,0 +1,95 @@
+package authn
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	sshaPrefix   = "{SSHA}"
+	bcryptPrefix = "{BCRYPT}"
+	saltLength   = 8
+)
+
+// HashSSHA returns a salted-SHA1 userPassword value: the RFC 2307
+// "{SSHA}" scheme used throughout OpenLDAP and AD-compatible
+// directories. The salt is newly generated each call.
+func HashSSHA(password string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	return hashSSHAWithSalt(password, salt), nil
+}
+
+func hashSSHAWithSalt(password string, salt []byte) string {
+	h := sha1.New()
+	h.Write([]byte(password))
+	h.Write(salt)
+	digest := h.Sum(nil)
+	return sshaPrefix + base64.StdEncoding.EncodeToString(append(digest, salt...))
+}
+
+// HashBCrypt returns a "{BCRYPT}"-prefixed bcrypt userPassword value
+// at the package default cost.
+func HashBCrypt(password string) (string, error) {
+	digest, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return bcryptPrefix + string(digest), nil
+}
+
+// VerifyPassword reports whether password matches a stored
+// userPassword value. It recognizes the "{SSHA}" and "{BCRYPT}"
+// schemes; any other value is compared as cleartext.
+func VerifyPassword(stored, password string) bool {
+	switch {
+	case strings.HasPrefix(stored, sshaPrefix):
+		return verifySSHA(strings.TrimPrefix(stored, sshaPrefix), password)
+	case strings.HasPrefix(stored, bcryptPrefix):
+		return bcrypt.CompareHashAndPassword([]byte(strings.TrimPrefix(stored, bcryptPrefix)), []byte(password)) == nil
+	default:
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(password)) == 1
+	}
+}
+
+// verifySSHA decodes an {SSHA} value's base64 payload, splits it into
+// its trailing saltLength-byte salt and leading SHA-1 digest, and
+// constant-time compares against sha1(password||salt).
+func verifySSHA(encoded, password string) bool {
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return false
+	}
+	if len(raw) <= saltLength {
+		return false
+	}
+	digest, salt := raw[:len(raw)-saltLength], raw[len(raw)-saltLength:]
+
+	h := sha1.New()
+	h.Write([]byte(password))
+	h.Write(salt)
+	want := h.Sum(nil)
+
+	return subtle.ConstantTimeCompare(want, digest) == 1
+}
+
+// HashPassword returns a userPassword value for password using the
+// named scheme ("ssha" or "bcrypt").
+func HashPassword(scheme, password string) (string, error) {
+	switch strings.ToLower(scheme) {
+	case "", "ssha":
+		return HashSSHA(password)
+	case "bcrypt":
+		return HashBCrypt(password)
+	default:
+		return "", fmt.Errorf("unknown hash scheme %q", scheme)
+	}
+}
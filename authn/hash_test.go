@@ -0,0 +1,117 @@
+package authn
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestHashSSHARoundTrip(t *testing.T) {
+	hash, err := HashSSHA("s3cr3t")
+	if err != nil {
+		t.Fatalf("HashSSHA: %v", err)
+	}
+	if !strings.HasPrefix(hash, sshaPrefix) {
+		t.Fatalf("HashSSHA(%q) = %q, want %q prefix", "s3cr3t", hash, sshaPrefix)
+	}
+	if !VerifyPassword(hash, "s3cr3t") {
+		t.Errorf("VerifyPassword(%q, correct password) = false, want true", hash)
+	}
+	if VerifyPassword(hash, "wrong") {
+		t.Errorf("VerifyPassword(%q, wrong password) = true, want false", hash)
+	}
+}
+
+func TestHashSSHAUniqueSalt(t *testing.T) {
+	a, err := HashSSHA("s3cr3t")
+	if err != nil {
+		t.Fatalf("HashSSHA: %v", err)
+	}
+	b, err := HashSSHA("s3cr3t")
+	if err != nil {
+		t.Fatalf("HashSSHA: %v", err)
+	}
+	if a == b {
+		t.Errorf("HashSSHA(%q) produced identical output twice; salt isn't varying", "s3cr3t")
+	}
+}
+
+func TestVerifySSHA(t *testing.T) {
+	salt := []byte("saltsalt")
+	encoded := hashSSHAWithSalt("s3cr3t", salt)
+
+	tests := []struct {
+		name     string
+		stored   string
+		password string
+		want     bool
+	}{
+		{"correct password", encoded, "s3cr3t", true},
+		{"wrong password", encoded, "wrong", false},
+		{"not valid base64", sshaPrefix + "!!!not-base64!!!", "s3cr3t", false},
+		{"payload exactly saltLength bytes", sshaPrefix + base64.StdEncoding.EncodeToString(salt), "s3cr3t", false},
+		{"payload shorter than saltLength", sshaPrefix + base64.StdEncoding.EncodeToString(salt[:saltLength-1]), "s3cr3t", false},
+		{"empty payload", sshaPrefix, "s3cr3t", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyPassword(tt.stored, tt.password); got != tt.want {
+				t.Errorf("VerifyPassword(%q, %q) = %v, want %v", tt.stored, tt.password, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashBCryptRoundTrip(t *testing.T) {
+	hash, err := HashBCrypt("s3cr3t")
+	if err != nil {
+		t.Fatalf("HashBCrypt: %v", err)
+	}
+	if !strings.HasPrefix(hash, bcryptPrefix) {
+		t.Fatalf("HashBCrypt(%q) = %q, want %q prefix", "s3cr3t", hash, bcryptPrefix)
+	}
+	if !VerifyPassword(hash, "s3cr3t") {
+		t.Errorf("VerifyPassword(%q, correct password) = false, want true", hash)
+	}
+	if VerifyPassword(hash, "wrong") {
+		t.Errorf("VerifyPassword(%q, wrong password) = true, want false", hash)
+	}
+}
+
+func TestVerifyPasswordCleartext(t *testing.T) {
+	if !VerifyPassword("s3cr3t", "s3cr3t") {
+		t.Errorf("VerifyPassword(cleartext, correct password) = false, want true")
+	}
+	if VerifyPassword("s3cr3t", "wrong") {
+		t.Errorf("VerifyPassword(cleartext, wrong password) = true, want false")
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	tests := []struct {
+		scheme string
+		prefix string
+	}{
+		{"", sshaPrefix},
+		{"ssha", sshaPrefix},
+		{"SSHA", sshaPrefix},
+		{"bcrypt", bcryptPrefix},
+		{"BCrypt", bcryptPrefix},
+	}
+	for _, tt := range tests {
+		t.Run(tt.scheme, func(t *testing.T) {
+			hash, err := HashPassword(tt.scheme, "s3cr3t")
+			if err != nil {
+				t.Fatalf("HashPassword(%q, ...): %v", tt.scheme, err)
+			}
+			if !strings.HasPrefix(hash, tt.prefix) {
+				t.Errorf("HashPassword(%q, ...) = %q, want %q prefix", tt.scheme, hash, tt.prefix)
+			}
+		})
+	}
+
+	if _, err := HashPassword("rot13", "s3cr3t"); err == nil {
+		t.Errorf("HashPassword(%q, ...) = nil error, want error for unknown scheme", "rot13")
+	}
+}
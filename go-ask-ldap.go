@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -14,7 +13,12 @@ import (
 	"strings"
 	"time"
 
+	ber "gopkg.in/asn1-ber.v1"
 	"gopkg.in/ldap.v0"
+
+	"github.com/johnweldon/go-ask-ldap/authn"
+	"github.com/johnweldon/go-ask-ldap/logging"
+	"github.com/johnweldon/go-ask-ldap/server"
 )
 
 const (
@@ -25,6 +29,10 @@ var (
 	config            Config
 	writeconfig       bool
 	configfile        string
+	verify            bool
+	hashPassword      string
+	hashScheme        string
+	logger            *logging.Logger
 	allAttributes     []string = []string{"*"}
 	shortAttributes   []string = []string{"cn", "distinguishedName"}
 	defaultAttributes []string = []string{
@@ -68,6 +76,19 @@ type Config struct {
 	UseTLS    bool
 	Username  string
 	Verbosity int
+
+	// Mode is set to "serve" when the program is started with the serve
+	// subcommand; a Mode read from the config file selects nothing.
+	Mode       string
+	Suffix     string
+	Bind       string
+	BindSecure bool
+	ServeCert  string
+	ServeKey   string
+	ACL        []server.ACLEntry
+	Backend    string
+
+	LogLevel string
 }
 
 func init() {
@@ -80,6 +101,23 @@ func init() {
 	flag.StringVar(&config.Password, "password", "", "password (secret)")
 	flag.StringVar(&configfile, "configfile", "~/.go-ask-ldap.conf", "config file in JSON format")
 	flag.IntVar(&config.Verbosity, "verbosity", 1, "0,1,2 are the options")
+
+	flag.StringVar(&config.Suffix, "suffix", "", "suffix (naming context) the serve mode answers for, e.g. dc=example,dc=org")
+	flag.StringVar(&config.Bind, "serveBind", ":389", "host:port the serve mode listens on")
+	flag.BoolVar(&config.BindSecure, "serveBindSecure", false, "'true' to require TLS on the serve listener")
+	flag.StringVar(&config.ServeCert, "serveCert", "", "PEM certificate file for -serveBindSecure")
+	flag.StringVar(&config.ServeKey, "serveKey", "", "PEM private key file for -serveBindSecure")
+	flag.StringVar(&config.Backend, "backend", "memory", "serve mode storage backend: 'memory' or 'consul'")
+
+	flag.BoolVar(&verify, "verify", false, "verify -username/-password by binding as that user instead of searching")
+	flag.StringVar(&hashPassword, "hash-password", "", "hash this password instead of connecting, using -hash-scheme")
+	flag.StringVar(&hashScheme, "hash-scheme", "ssha", "scheme for -hash-password: 'ssha' or 'bcrypt'")
+
+	defaultLogLevel := "info"
+	if env := os.Getenv("GO_ASK_LDAP_LOG_LEVEL"); env != "" {
+		defaultLogLevel = env
+	}
+	flag.StringVar(&config.LogLevel, "logLevel", defaultLogLevel, "trace, debug, info, warn, error, or fatal (also via GO_ASK_LDAP_LOG_LEVEL)")
 }
 
 func resolvePath(path string) string {
@@ -112,7 +150,35 @@ func readConfig() {
 
 func main() {
 	readConfig()
+
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		config.Mode = "serve"
+		flag.CommandLine.Parse(os.Args[2:])
+		logger = logging.Default(logging.ParseLevel(config.LogLevel))
+		runServer()
+		return
+	}
+
 	flag.Parse()
+	logger = logging.Default(logging.ParseLevel(config.LogLevel))
+
+	if hashPassword != "" {
+		hash, err := authn.HashPassword(hashScheme, hashPassword)
+		if err != nil {
+			logger.Fatalf("HASH: %+v", err)
+		}
+		fmt.Fprintln(os.Stdout, hash)
+		return
+	}
+
+	if verify {
+		if err := authn.VerifyBind(config.Hostname, config.Port, config.UseTLS, config.Username, config.Password); err != nil {
+			fmt.Fprintf(os.Stdout, "VERIFY:: FAILED: %+v\n", err)
+			os.Exit(1)
+		}
+		fmt.Fprintln(os.Stdout, "VERIFY:: OK")
+		return
+	}
 
 	conn := connect()
 	defer conn.Close()
@@ -123,34 +189,85 @@ func main() {
 	}
 }
 
-func search(conn *ldap.Conn, search string) {
-	request := ldap.NewSearchRequest(config.BaseDn, ldap.ScopeWholeSubtree, ldap.DerefAlways, 10000, 30, false, search, verbosity[config.Verbosity], []ldap.Control{})
-	result, err := conn.Search(request)
+// runServer starts the LDAP frontend in "serve" mode, backed by the
+// storage named in config.Backend. config.Suffix roots the consul
+// backend's keys; requests for DNs outside it are not rejected.
+func runServer() {
+	backend, err := newBackend(config.Backend)
 	if err != nil {
-		log.Printf("ERROR:: %+v\n", err)
-		return
+		logger.Fatalf("BACKEND: %+v", err)
 	}
-	fmt.Fprintf(os.Stdout, "SEARCH:: '%s'\nRESULT::\n%+v\n", search, result)
-	for _, entry := range result.Entries {
-		fmt.Fprintf(os.Stdout, "ENTRY: '%s'\n", entry.DN)
-		for _, attribute := range entry.Attributes {
-			fmt.Fprintf(os.Stdout, "    %20s:", attribute.Name)
-			if len(attribute.Values) == 1 {
-				fmt.Fprintf(os.Stdout, " %s\n", display(attribute.Name)(attribute.Values[0]))
-			} else {
-				fmt.Fprintf(os.Stdout, "\n")
-				for _, val := range attribute.Values {
-					fmt.Fprintf(os.Stdout, "    %20s  %s\n", "", display(attribute.Name)(val))
-				}
-			}
+
+	var tlsConfig *tls.Config
+	if config.BindSecure {
+		tlsConfig, err = serveTLSConfig(config.ServeCert, config.ServeKey)
+		if err != nil {
+			logger.Fatalf("TLS: %+v", err)
 		}
 	}
+
+	srv := server.New(server.Options{
+		Suffix:     config.Suffix,
+		Backend:    backend,
+		ACL:        config.ACL,
+		BindSecure: config.BindSecure,
+		TLSConfig:  tlsConfig,
+	})
+
+	if err := srv.ListenAndServe(config.Bind); err != nil {
+		logger.Fatalf("SERVE: %+v", err)
+	}
+}
+
+// serveTLSConfig loads the -serveCert/-serveKey PEM pair required to
+// terminate TLS on the serve listener.
+func serveTLSConfig(certFile, keyFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, fmt.Errorf("-serveCert and -serveKey are required with -serveBindSecure")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load cert/key: %v", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}}, nil
+}
+
+// newBackend constructs the configured server.Backend implementation.
+func newBackend(name string) (server.Backend, error) {
+	switch name {
+	case "", "memory":
+		return server.NewMemoryBackend(), nil
+	case "consul":
+		return server.NewConsulBackend(os.Getenv("CONSUL_HTTP_ADDR"), config.Suffix)
+	default:
+		return nil, fmt.Errorf("unknown backend %q", name)
+	}
+}
+
+// hexDumpSearchRequest renders a search request's base DN and filter as
+// a BER SEQUENCE, for trace-level diagnostics against unfamiliar
+// directories.
+func hexDumpSearchRequest(request *ldap.SearchRequest) string {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "SearchRequest")
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, request.BaseDN, "baseObject"))
+	packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, request.Filter, "filter"))
+	return hex.Dump(packet.Bytes())
+}
+
+// hexDumpSearchResult renders a summary of a search response as a BER
+// SEQUENCE of entry DNs, for trace-level diagnostics.
+func hexDumpSearchResult(result *ldap.SearchResult) string {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "SearchResult")
+	for _, entry := range result.Entries {
+		packet.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "objectName"))
+	}
+	return hex.Dump(packet.Bytes())
 }
 
 func connect() *ldap.Conn {
 	conn := connectFn(config.UseTLS)()
 	if err := conn.Bind(config.Username, config.Password); err != nil {
-		log.Fatal("BIND: ", err)
+		logger.Fatalf("BIND: %+v", err)
 	}
 	return conn
 }
@@ -166,22 +283,41 @@ func connectTLS() *ldap.Conn {
 	tlsConfig := &tls.Config{InsecureSkipVerify: true}
 	conn, err := ldap.DialTLS("tcp", fmt.Sprintf("%s:%d", config.Hostname, config.Port), tlsConfig)
 	if err != nil {
-		log.Fatal("CONNECT TLS: ", err)
+		logger.Fatalf("CONNECT TLS: %+v", err)
 	}
 	return conn
 }
 func connectPlain() *ldap.Conn {
 	conn, err := ldap.Dial("tcp", fmt.Sprintf("%s:%d", config.Hostname, config.Port))
 	if err != nil {
-		log.Fatal("CONNECT PLAIN: ", err)
+		logger.Fatalf("CONNECT PLAIN: %+v", err)
 	}
 	return conn
 }
 
-func display(key string) func(string) string {
+// display returns the decoder for key: a function taking the raw
+// attribute value string and returning both a typed value (for output
+// formats like JSON that can carry proper types) and its display
+// string (for the default text output).
+func display(key string) func(string) (interface{}, string) {
+	if rawOutput {
+		switch key {
+		case "jpegPhoto", "objectGUID", "objectSid":
+			return displayBinaryFn
+		case "userAccountControl":
+			return displayStringFn
+		}
+	}
+
 	switch key {
-	case "jpegPhoto", "objectGUID", "objectSid":
+	case "jpegPhoto":
 		return displayBinaryFn
+	case "objectGUID":
+		return displayGUIDFn
+	case "objectSid":
+		return displaySIDFn
+	case "userAccountControl":
+		return displayUserAccountControlFn
 	case "accountExpires", "lastLogon", "lockoutTime", "lastLogonTimestamp", "pwdLastSet", "badPasswordTime":
 		return displayTimestampFn
 	case "whenCreated":
@@ -191,36 +327,37 @@ func display(key string) func(string) string {
 	}
 }
 
-func displayBinaryFn(val string) string {
+func displayBinaryFn(val string) (interface{}, string) {
+	raw := []byte(val)
 	var buf []byte
-	if len(val) > 0x1f {
-		buf = []byte(val)[:0x1f]
+	if len(raw) > 0x1f {
+		buf = raw[:0x1f]
 	} else {
-		buf = []byte(val)
+		buf = raw
 	}
-	return fmt.Sprintf("<binary %d bytes> '%s'", len(val), hex.EncodeToString(buf))
+	return raw, fmt.Sprintf("<binary %d bytes> '%s'", len(val), hex.EncodeToString(buf))
 }
 
-func displayStringFn(val string) string {
-	return fmt.Sprintf("'%s'", val)
+func displayStringFn(val string) (interface{}, string) {
+	return val, fmt.Sprintf("'%s'", val)
 }
 
-func displayTimeFmtFn(val string) string {
+func displayTimeFmtFn(val string) (interface{}, string) {
 	if t, e := time.ParseInLocation("20060102150405.0Z", val, time.UTC); e == nil {
-		return t.String()
+		return t, t.String()
 	}
-	return val
+	return val, val
 }
 
-func displayTimestampFn(val string) string {
+func displayTimestampFn(val string) (interface{}, string) {
 	tsval, err := strconv.ParseInt(val, 10, 64)
 	if err != nil {
-		return fmt.Sprintf("ERROR: '%s'", err)
+		return nil, fmt.Sprintf("ERROR: '%s'", err)
 	}
 	if tsval == 0 {
-		return "n/a"
+		return nil, "n/a"
 	}
 	epoch := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
 	t := epoch.Add(time.Duration((tsval - unixepoch) * 100))
-	return t.String()
+	return t, t.String()
 }
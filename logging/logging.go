@@ -0,0 +1,100 @@
+// Package logging provides the leveled logger used throughout
+// go-ask-ldap in place of ad-hoc log.Printf/log.Fatal calls, in the
+// trace/debug/info/warn/error/fatal scheme bottin uses around logrus.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// Level is a logging severity. Levels are ordered trace < debug <
+// info < warn < error < fatal; a Logger emits a message only when its
+// level is at or above the configured threshold.
+type Level int
+
+const (
+	LevelTrace Level = iota
+	LevelDebug
+	LevelInfo
+	LevelWarn
+	LevelError
+	LevelFatal
+)
+
+func (l Level) String() string {
+	switch l {
+	case LevelTrace:
+		return "TRACE"
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	case LevelFatal:
+		return "FATAL"
+	default:
+		return "INFO"
+	}
+}
+
+// ParseLevel parses a level name case-insensitively, defaulting to
+// LevelInfo for an empty or unrecognized string.
+func ParseLevel(name string) Level {
+	switch strings.ToLower(name) {
+	case "trace":
+		return LevelTrace
+	case "debug":
+		return LevelDebug
+	case "warn", "warning":
+		return LevelWarn
+	case "error":
+		return LevelError
+	case "fatal":
+		return LevelFatal
+	default:
+		return LevelInfo
+	}
+}
+
+// Logger writes leveled messages to an underlying *log.Logger,
+// dropping anything below its configured Level.
+type Logger struct {
+	level Level
+	std   *log.Logger
+}
+
+// New returns a Logger at level, writing to out.
+func New(level Level, out io.Writer) *Logger {
+	return &Logger{level: level, std: log.New(out, "", log.LstdFlags)}
+}
+
+// Default returns a Logger at level, writing to os.Stderr.
+func Default(level Level) *Logger {
+	return New(level, os.Stderr)
+}
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+	l.std.Printf("%s:: %s\n", level, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Tracef(format string, args ...interface{}) { l.logf(LevelTrace, format, args...) }
+func (l *Logger) Debugf(format string, args ...interface{}) { l.logf(LevelDebug, format, args...) }
+func (l *Logger) Infof(format string, args ...interface{})  { l.logf(LevelInfo, format, args...) }
+func (l *Logger) Warnf(format string, args ...interface{})  { l.logf(LevelWarn, format, args...) }
+func (l *Logger) Errorf(format string, args ...interface{}) { l.logf(LevelError, format, args...) }
+
+// Fatalf logs at LevelFatal and then exits the process, mirroring
+// log.Fatal's behavior regardless of the configured level.
+func (l *Logger) Fatalf(format string, args ...interface{}) {
+	l.std.Fatalf("%s:: %s\n", LevelFatal, fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"gopkg.in/ldap.v0"
+)
+
+var (
+	output       string
+	columns      string
+	csvSeparator string
+)
+
+func init() {
+	flag.StringVar(&output, "output", "text", "output format for search results: text, json, ldif, or csv")
+	flag.StringVar(&columns, "columns", "", "comma-separated attribute list for -output csv")
+	flag.StringVar(&csvSeparator, "separator", ";", "separator used to join multi-valued attributes in -output csv")
+}
+
+// binarySyntaxAttributes are rendered as hex (json) or base64 (ldif)
+// rather than as text, matching their LDAP binary syntax.
+var binarySyntaxAttributes = map[string]bool{
+	"jpegPhoto":  true,
+	"objectGUID": true,
+	"objectSid":  true,
+}
+
+// writeEntries renders entries to w in the configured output format.
+// It is the single fan-out point emitResult uses for each result set
+// (or page) that comes back, so every format sees the same entries.
+func writeEntries(w io.Writer, entries []*ldap.Entry) error {
+	switch output {
+	case "json":
+		return writeJSONEntries(w, entries)
+	case "ldif":
+		return writeLDIFEntries(w, entries)
+	case "csv":
+		return writeCSVEntries(w, entries)
+	default:
+		return writeTextEntries(w, entries)
+	}
+}
+
+// writeTextEntries reproduces the tool's original human-readable
+// listing.
+func writeTextEntries(w io.Writer, entries []*ldap.Entry) error {
+	for _, entry := range entries {
+		fmt.Fprintf(w, "ENTRY: '%s'\n", entry.DN)
+		for _, attribute := range entry.Attributes {
+			decode := display(attribute.Name)
+			fmt.Fprintf(w, "    %20s:", attribute.Name)
+			if len(attribute.Values) == 1 {
+				_, str := decode(attribute.Values[0])
+				fmt.Fprintf(w, " %s\n", str)
+			} else {
+				fmt.Fprintf(w, "\n")
+				for _, val := range attribute.Values {
+					_, str := decode(val)
+					fmt.Fprintf(w, "    %20s  %s\n", "", str)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// writeJSONEntries streams one JSON object per entry (JSONL): binary
+// attributes are hex-encoded, and attributes with time-typed display
+// values are rendered as RFC3339 using the existing display()
+// conversions so callers get proper types without reparsing text.
+func writeJSONEntries(w io.Writer, entries []*ldap.Entry) error {
+	encoder := json.NewEncoder(w)
+	for _, entry := range entries {
+		doc := map[string]interface{}{"dn": entry.DN}
+		for _, attribute := range entry.Attributes {
+			values := make([]interface{}, len(attribute.Values))
+			for i, val := range attribute.Values {
+				values[i] = jsonValue(attribute.Name, val)
+			}
+			if len(values) == 1 {
+				doc[attribute.Name] = values[0]
+			} else {
+				doc[attribute.Name] = values
+			}
+		}
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func jsonValue(name, val string) interface{} {
+	if binarySyntaxAttributes[name] {
+		return hex.EncodeToString([]byte(val))
+	}
+	raw, str := display(name)(val)
+	if t, ok := raw.(time.Time); ok {
+		return t.Format(time.RFC3339)
+	}
+	if raw == nil {
+		return str
+	}
+	return raw
+}
+
+// writeLDIFEntries emits RFC 2849 records: a dn: (or base64 dn::) line,
+// one attr: (or attr::) line per value, and a blank line between
+// entries.
+func writeLDIFEntries(w io.Writer, entries []*ldap.Entry) error {
+	for _, entry := range entries {
+		writeLDIFLine(w, "dn", entry.DN)
+		for _, attribute := range entry.Attributes {
+			for _, val := range attribute.Values {
+				writeLDIFLine(w, attribute.Name, val)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	return nil
+}
+
+// writeLDIFLine emits a single "attr: value" line, switching to the
+// base64 "attr::" form whenever value isn't an RFC 2849 SAFE-STRING:
+// binary-syntax attributes, or values containing NUL/CR/LF or any
+// non-ASCII character, or starting with a space, colon or "<".
+func writeLDIFLine(w io.Writer, attr, val string) {
+	if needsBase64(attr, val) {
+		fmt.Fprintf(w, "%s:: %s\n", attr, base64.StdEncoding.EncodeToString([]byte(val)))
+		return
+	}
+	fmt.Fprintf(w, "%s: %s\n", attr, val)
+}
+
+func needsBase64(attr, val string) bool {
+	if binarySyntaxAttributes[attr] {
+		return true
+	}
+	if val == "" {
+		return false
+	}
+	if strings.HasPrefix(val, " ") || strings.HasPrefix(val, ":") || strings.HasPrefix(val, "<") {
+		return true
+	}
+	for _, r := range val {
+		if r == 0 || r == '\r' || r == '\n' || r > 0x7f {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCSVEntries takes the -columns list (all attribute names seen,
+// in first-seen order, when -columns is empty) and emits a header row
+// plus one row per entry, joining multi-valued attributes with
+// csvSeparator.
+func writeCSVEntries(w io.Writer, entries []*ldap.Entry) error {
+	cols := strings.Split(columns, ",")
+	if columns == "" {
+		cols = collectColumns(entries)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append([]string{"dn"}, cols...)); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		row := make([]string, 0, len(cols)+1)
+		row = append(row, entry.DN)
+		for _, col := range cols {
+			row = append(row, joinCSVValues(entry, col))
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func joinCSVValues(entry *ldap.Entry, attr string) string {
+	for _, attribute := range entry.Attributes {
+		if attribute.Name != attr {
+			continue
+		}
+		values := make([]string, len(attribute.Values))
+		for i, val := range attribute.Values {
+			_, str := display(attr)(val)
+			values[i] = str
+		}
+		return strings.Join(values, csvSeparator)
+	}
+	return ""
+}
+
+func collectColumns(entries []*ldap.Entry) []string {
+	seen := map[string]bool{}
+	var cols []string
+	for _, entry := range entries {
+		for _, attribute := range entry.Attributes {
+			if seen[attribute.Name] {
+				continue
+			}
+			seen[attribute.Name] = true
+			cols = append(cols, attribute.Name)
+		}
+	}
+	return cols
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+
+	"gopkg.in/ldap.v0"
+)
+
+// maxReferralDepth bounds how many hops -follow-referrals will chase,
+// so a misconfigured or malicious pair of servers referring back to
+// each other can't loop forever.
+const maxReferralDepth = 5
+
+var (
+	pageSize        int
+	followReferrals bool
+)
+
+func init() {
+	flag.IntVar(&pageSize, "page-size", 0, "page results using the LDAP Simple Paged Results control (RFC 2696); 0 issues a single unpaged request")
+	flag.BoolVar(&followReferrals, "follow-referrals", false, "dial and re-bind to any LDAPResultReferral targets returned by a search")
+}
+
+// search issues term against conn. When -page-size is set it pages
+// through the result via the RFC 2696 control, streaming each page's
+// entries as it arrives rather than buffering sizeLimit-many results;
+// when -follow-referrals is set it also dials and continues the
+// search at any referral targets the server returns.
+func search(conn *ldap.Conn, term string) {
+	attributes := verbosity[config.Verbosity]
+	request := ldap.NewSearchRequest(config.BaseDn, ldap.ScopeWholeSubtree, ldap.DerefAlways, 10000, 30, false, term, attributes, []ldap.Control{})
+
+	logger.Debugf("bindDN=%q base=%q scope=wholeSubtree filter=%q", config.Username, config.BaseDn, term)
+	logger.Tracef("outgoing search request:\n%s", hexDumpSearchRequest(request))
+
+	if output == "text" {
+		fmt.Fprintf(os.Stdout, "SEARCH:: '%s'\n", term)
+	}
+
+	runSearch(conn, request, term, 0)
+}
+
+// runSearch performs one paged (or single-shot) search loop against
+// conn and, on success, chases any referrals the server returned.
+func runSearch(conn *ldap.Conn, request *ldap.SearchRequest, term string, referralDepth int) {
+	if pageSize <= 0 {
+		result, err := conn.Search(request)
+		if err != nil {
+			// A whole-search redirect (resultCode 10) comes back from
+			// gopkg.in/ldap.v0 as an *ldap.Error, with result still
+			// holding any SearchResultReference referrals seen before
+			// it; that library version never decodes referral URIs
+			// out of the resultDone PDU itself, so a bare
+			// LDAPResultReferral with no prior references can't be
+			// chased here, only reported.
+			if ldapErr, ok := err.(*ldap.Error); ok && ldapErr.ResultCode == ldap.LDAPResultReferral {
+				if result != nil && len(result.Referrals) > 0 {
+					chaseReferrals(result.Referrals, request, term, referralDepth)
+					return
+				}
+				logger.Warnf("search %q redirected (LDAPResultReferral) with no referral URIs to follow", term)
+				return
+			}
+			logger.Errorf("%+v", err)
+			return
+		}
+		emitResult(term, result, request, referralDepth)
+		return
+	}
+
+	// Copy request rather than mutating it in place: request may be the
+	// same *SearchRequest a referral hop higher up is still using to
+	// page its own search, and appending our paging control to its
+	// Controls would leave that hop's next iteration carrying ours too.
+	paged := *request
+	paging := ldap.NewControlPaging(uint32(pageSize))
+	paged.Controls = append(append([]ldap.Control{}, request.Controls...), paging)
+
+	for {
+		result, err := conn.Search(&paged)
+		if err != nil {
+			logger.Errorf("%+v", err)
+			return
+		}
+		// Chase referrals against the pristine request, not paged: a
+		// referral hop starts its own paged search from scratch and
+		// must not inherit this hop's paging control/cookie.
+		emitResult(term, result, request, referralDepth)
+
+		cookie := pagingCookie(result.Controls)
+		if len(cookie) == 0 {
+			return
+		}
+		paging.SetCookie(cookie)
+	}
+}
+
+// pagingCookie extracts the RFC 2696 paging cookie from a search
+// response's controls, returning nil once the server has no more
+// pages to send.
+func pagingCookie(controls []ldap.Control) []byte {
+	for _, control := range controls {
+		if paging, ok := control.(*ldap.ControlPaging); ok {
+			return paging.Cookie
+		}
+	}
+	return nil
+}
+
+// emitResult logs and renders one page (or the whole result, when
+// unpaged) of a search, then chases any referrals it carries.
+func emitResult(term string, result *ldap.SearchResult, request *ldap.SearchRequest, referralDepth int) {
+	logger.Tracef("incoming search response:\n%s", hexDumpSearchResult(result))
+	logger.Infof("search %q returned %d entries", term, len(result.Entries))
+	for _, entry := range result.Entries {
+		logger.Debugf("entry %q has %d attributes", entry.DN, len(entry.Attributes))
+	}
+
+	if err := writeEntries(os.Stdout, result.Entries); err != nil {
+		logger.Errorf("%+v", err)
+	}
+
+	if len(result.Referrals) > 0 {
+		chaseReferrals(result.Referrals, request, term, referralDepth)
+	}
+}
+
+// chaseReferrals dials and re-binds to each referral URL when
+// -follow-referrals is set and referralDepth hasn't hit
+// maxReferralDepth, continuing the same search there; otherwise it
+// just logs that referrals were left unfollowed.
+func chaseReferrals(referrals []string, request *ldap.SearchRequest, term string, referralDepth int) {
+	if !followReferrals || referralDepth >= maxReferralDepth {
+		logger.Warnf("search %q returned %d referral(s), not following (depth=%d)", term, len(referrals), referralDepth)
+		return
+	}
+
+	for _, referral := range referrals {
+		referralConn, err := dialReferral(referral)
+		if err != nil {
+			logger.Errorf("referral %q: %+v", referral, err)
+			continue
+		}
+		if err := referralConn.Bind(config.Username, config.Password); err != nil {
+			logger.Errorf("referral %q bind: %+v", referral, err)
+			referralConn.Close()
+			continue
+		}
+
+		logger.Infof("following referral %q (depth=%d)", referral, referralDepth+1)
+		runSearch(referralConn, request, term, referralDepth+1)
+		referralConn.Close()
+	}
+}
+
+// dialReferral dials the host:port named by an ldap:// or ldaps://
+// referral URL, defaulting to the scheme's standard port when the URL
+// doesn't specify one explicitly.
+func dialReferral(raw string) (*ldap.Conn, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parse referral: %v", err)
+	}
+
+	host, port := u.Hostname(), u.Port()
+	switch u.Scheme {
+	case "ldaps":
+		if port == "" {
+			port = "636"
+		}
+		return ldap.DialTLS("tcp", net.JoinHostPort(host, port), &tls.Config{InsecureSkipVerify: true})
+	case "ldap", "":
+		if port == "" {
+			port = "389"
+		}
+		return ldap.Dial("tcp", net.JoinHostPort(host, port))
+	default:
+		return nil, fmt.Errorf("unsupported referral scheme %q", u.Scheme)
+	}
+}
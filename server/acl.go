@@ -0,0 +1,62 @@
+package server
+
+import "strings"
+
+// Right is an access level granted to a bound identity by an ACLEntry.
+type Right string
+
+const (
+	RightRead  Right = "read"
+	RightWrite Right = "write"
+	RightAdmin Right = "admin"
+)
+
+// ACLEntry grants Rights to any bound DN matching Pattern, where
+// Pattern is a DN with a leading "*" wildcard RDN, e.g.
+// "*,ou=users,dc=example,dc=org" matches any direct child of that OU.
+type ACLEntry struct {
+	Pattern string
+	Rights  []Right
+}
+
+// allowed reports whether boundDN has right under any of acl's entries.
+// boundDN == "" (an anonymous bind) is matched like any other bound
+// identity: it only has whatever rights an entry's Pattern grants it,
+// so an operator who wants anonymous access restricted to read must
+// not grant write/admin to a pattern that also matches "".
+func allowed(acl []ACLEntry, boundDN string, right Right) bool {
+	for _, entry := range acl {
+		if matchesPattern(entry.Pattern, boundDN) && hasRight(entry.Rights, right) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasRight(rights []Right, want Right) bool {
+	for _, r := range rights {
+		if r == want || r == RightAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesPattern matches dn against pattern, where a leading "*,"
+// in pattern matches exactly one RDN of dn, and a bare "*" matches
+// any DN.
+func matchesPattern(pattern, dn string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if !strings.HasPrefix(pattern, "*,") {
+		return strings.EqualFold(pattern, dn)
+	}
+	suffix := pattern[2:]
+	if !strings.HasSuffix(strings.ToLower(dn), ","+strings.ToLower(suffix)) && !strings.EqualFold(dn, suffix) {
+		return false
+	}
+	rdns := splitDN(dn)
+	suffixRDNs := splitDN(suffix)
+	return len(rdns) == len(suffixRDNs)+1
+}
@@ -0,0 +1,56 @@
+package server
+
+import "testing"
+
+func TestMatchesPattern(t *testing.T) {
+	tests := []struct {
+		name    string
+		pattern string
+		dn      string
+		want    bool
+	}{
+		{"bare wildcard matches anything", "*", "cn=alice,ou=users,dc=example,dc=org", true},
+		{"exact match", "cn=alice,ou=users,dc=example,dc=org", "cn=alice,ou=users,dc=example,dc=org", true},
+		{"exact match case-insensitive", "CN=Alice,OU=Users,DC=Example,DC=Org", "cn=alice,ou=users,dc=example,dc=org", true},
+		{"exact mismatch", "cn=bob,ou=users,dc=example,dc=org", "cn=alice,ou=users,dc=example,dc=org", false},
+		{"suffix wildcard matches one RDN", "*,ou=users,dc=example,dc=org", "cn=alice,ou=users,dc=example,dc=org", true},
+		{"suffix wildcard rejects deeper RDN", "*,ou=users,dc=example,dc=org", "cn=alice,ou=bots,ou=users,dc=example,dc=org", false},
+		{"suffix wildcard rejects unrelated suffix", "*,ou=users,dc=example,dc=org", "cn=alice,ou=groups,dc=example,dc=org", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := matchesPattern(tt.pattern, tt.dn); got != tt.want {
+				t.Errorf("matchesPattern(%q, %q) = %v, want %v", tt.pattern, tt.dn, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	acl := []ACLEntry{
+		{Pattern: "*,ou=admins,dc=example,dc=org", Rights: []Right{RightAdmin}},
+		{Pattern: "*,ou=users,dc=example,dc=org", Rights: []Right{RightRead}},
+	}
+
+	tests := []struct {
+		name    string
+		boundDN string
+		right   Right
+		want    bool
+	}{
+		{"admin has write via admin right", "cn=root,ou=admins,dc=example,dc=org", RightWrite, true},
+		{"user has read", "cn=alice,ou=users,dc=example,dc=org", RightRead, true},
+		{"user lacks write", "cn=alice,ou=users,dc=example,dc=org", RightWrite, false},
+		{"anonymous bind has no grants here", "", RightRead, false},
+		{"unrelated DN has no grants", "cn=alice,ou=other,dc=example,dc=org", RightRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := allowed(acl, tt.boundDN, tt.right); got != tt.want {
+				t.Errorf("allowed(acl, %q, %q) = %v, want %v", tt.boundDN, tt.right, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package server
+
+import "strings"
+
+// Entry is a single directory entry as stored by a Backend: its DN plus
+// a multi-valued attribute map, JSON-marshaled verbatim into the KV
+// store's blob.
+type Entry struct {
+	DN         string              `json:"dn"`
+	Attributes map[string][]string `json:"attributes"`
+}
+
+// Backend is the storage interface a Server operates against. Entries
+// are addressed by the key DNToKey derives from their DN, so backends
+// never need to parse DNs themselves.
+type Backend interface {
+	Get(key string) (*Entry, error)
+	Put(key string, entry *Entry) error
+	Delete(key string) error
+	// List returns every entry whose key falls under base (inclusive),
+	// for the server to filter against during a search.
+	List(base string) ([]*Entry, error)
+}
+
+// ErrNotFound is returned by Backend.Get and Backend.Delete when key
+// has no stored entry.
+var ErrNotFound = errNotFound{}
+
+type errNotFound struct{}
+
+func (errNotFound) Error() string { return "entry not found" }
+
+// DNToKey derives the canonical storage key for dn: its RDNs, reversed
+// so that the suffix is the key prefix, joined with "/". This lets
+// List(base) use a simple string-prefix scan to find an entire subtree.
+//
+//	DNToKey("cn=alice,ou=users,dc=example,dc=org")
+//	  == "dc=org/dc=example/ou=users/cn=alice"
+func DNToKey(dn string) string {
+	rdns := splitDN(dn)
+	for i, j := 0, len(rdns)-1; i < j; i, j = i+1, j-1 {
+		rdns[i], rdns[j] = rdns[j], rdns[i]
+	}
+	return strings.Join(rdns, "/")
+}
+
+// splitDN splits dn on unescaped commas into its component RDNs.
+func splitDN(dn string) []string {
+	var rdns []string
+	var cur strings.Builder
+	escaped := false
+	for _, r := range dn {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			cur.WriteRune(r)
+			escaped = true
+		case r == ',':
+			rdns = append(rdns, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		rdns = append(rdns, strings.TrimSpace(cur.String()))
+	}
+	return rdns
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	consulapi "github.com/hashicorp/consul/api"
+)
+
+// ConsulBackend stores entries as JSON blobs under a KV path rooted at
+// DNToKey(suffix), one key per entry, so a whole subtree maps to a
+// single Consul KV prefix scan.
+type ConsulBackend struct {
+	kv     *consulapi.KV
+	prefix string
+}
+
+// NewConsulBackend dials the Consul agent at addr (the default address
+// is used when addr is empty) and roots all keys under suffix.
+func NewConsulBackend(addr, suffix string) (*ConsulBackend, error) {
+	cfg := consulapi.DefaultConfig()
+	if addr != "" {
+		cfg.Address = addr
+	}
+	client, err := consulapi.NewClient(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("consul client: %v", err)
+	}
+	return &ConsulBackend{kv: client.KV(), prefix: DNToKey(suffix)}, nil
+}
+
+func (c *ConsulBackend) path(key string) string {
+	return "go-ask-ldap/" + c.prefix + "/" + key
+}
+
+func (c *ConsulBackend) Get(key string) (*Entry, error) {
+	pair, _, err := c.kv.Get(c.path(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	if pair == nil {
+		return nil, ErrNotFound
+	}
+	var entry Entry
+	if err := json.Unmarshal(pair.Value, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (c *ConsulBackend) Put(key string, entry *Entry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = c.kv.Put(&consulapi.KVPair{Key: c.path(key), Value: raw}, nil)
+	return err
+}
+
+func (c *ConsulBackend) Delete(key string) error {
+	pair, _, err := c.kv.Get(c.path(key), nil)
+	if err != nil {
+		return err
+	}
+	if pair == nil {
+		return ErrNotFound
+	}
+	_, err = c.kv.Delete(c.path(key), nil)
+	return err
+}
+
+func (c *ConsulBackend) List(base string) ([]*Entry, error) {
+	prefix := c.path(DNToKey(base))
+	pairs, _, err := c.kv.List(prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for _, pair := range pairs {
+		if pair.Key != prefix && !strings.HasPrefix(pair.Key, prefix+"/") {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(pair.Value, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	ber "gopkg.in/asn1-ber.v1"
+	"gopkg.in/ldap.v0"
+)
+
+// Filter application tags, per RFC 4511 section 4.5.1.7.
+const (
+	filterAnd            = 0
+	filterOr             = 1
+	filterNot            = 2
+	filterEqualityMatch  = 3
+	filterSubstrings     = 4
+	filterGreaterOrEqual = 5
+	filterLessOrEqual    = 6
+	filterPresent        = 7
+	filterApproxMatch    = 8
+)
+
+// numericAttributes lists the attributes compared numerically under
+// >=/<=; everything else is compared as a DirectoryString (lexically,
+// case-insensitively).
+var numericAttributes = map[string]bool{
+	"uidNumber":          true,
+	"gidNumber":          true,
+	"userAccountControl": true,
+	"uSNCreated":         true,
+	"uSNChanged":         true,
+}
+
+// CompileFilter parses an RFC 4515 filter string into the ber.Packet
+// AST applyFilter walks.
+func CompileFilter(filter string) (*ber.Packet, error) {
+	return ldap.CompileFilter(filter)
+}
+
+// applyFilter recursively evaluates filter against entry, descending
+// into & | ! exactly as RFC 4511 specifies, and treating an attribute
+// missing from entry as false everywhere except inside a ! branch.
+func applyFilter(filter *ber.Packet, entry *Entry) bool {
+	switch filter.Tag {
+	case filterAnd:
+		for _, child := range filter.Children {
+			if !applyFilter(child, entry) {
+				return false
+			}
+		}
+		return true
+
+	case filterOr:
+		for _, child := range filter.Children {
+			if applyFilter(child, entry) {
+				return true
+			}
+		}
+		return false
+
+	case filterNot:
+		child, ok := childAt(filter, 0)
+		if !ok {
+			return false
+		}
+		return !applyFilter(child, entry)
+
+	case filterEqualityMatch, filterApproxMatch:
+		attr, value, ok := attributeAssertion(filter)
+		if !ok {
+			return false
+		}
+		return matchesAny(entry, attr, func(v string) bool { return equalFold(attr, v, value) })
+
+	case filterSubstrings:
+		return applySubstrings(filter, entry)
+
+	case filterGreaterOrEqual:
+		attr, value, ok := attributeAssertion(filter)
+		if !ok {
+			return false
+		}
+		return matchesAny(entry, attr, func(v string) bool { return compare(attr, v, value) >= 0 })
+
+	case filterLessOrEqual:
+		attr, value, ok := attributeAssertion(filter)
+		if !ok {
+			return false
+		}
+		return matchesAny(entry, attr, func(v string) bool { return compare(attr, v, value) <= 0 })
+
+	case filterPresent:
+		attr, ok := presentAttribute(filter)
+		if !ok {
+			return false
+		}
+		values, ok := entry.Attributes[attr]
+		return ok && len(values) > 0
+
+	default:
+		return false
+	}
+}
+
+// attributeAssertion decodes an AttributeValueAssertion SEQUENCE {
+// attributeDesc, assertionValue } shared by equalityMatch,
+// greaterOrEqual, lessOrEqual, and approxMatch.
+func attributeAssertion(filter *ber.Packet) (attr, value string, ok bool) {
+	attr, ok = childString(filter, 0)
+	if !ok {
+		return "", "", false
+	}
+	value, ok = childString(filter, 1)
+	return attr, value, ok
+}
+
+// presentAttribute decodes a present [7] AttributeDescription filter.
+// CompileFilter builds it as a constructed packet whose sole child
+// holds the attribute name (ber.NewString always sets .Value, so this
+// shape decodes fine via childString); a real client's present filter
+// instead arrives off the wire as a single IMPLICIT context-tagged
+// primitive with no children, where only Data (not Value) is
+// populated, so that shape is read directly off filter itself via
+// packetString.
+func presentAttribute(filter *ber.Packet) (string, bool) {
+	if len(filter.Children) > 0 {
+		return childString(filter, 0)
+	}
+	return packetString(filter)
+}
+
+// applySubstrings matches a substrings filter's initial/any/final
+// components in order, case-insensitively, against each value of attr.
+func applySubstrings(filter *ber.Packet, entry *Entry) bool {
+	attr, ok := childString(filter, 0)
+	if !ok {
+		return false
+	}
+	substrings, ok := childAt(filter, 1)
+	if !ok {
+		return false
+	}
+	var initial, final string
+	var any []string
+	for _, part := range substrings.Children {
+		raw, ok := packetString(part)
+		if !ok {
+			continue
+		}
+		val := strings.ToLower(raw)
+		switch part.Tag {
+		case 0:
+			initial = val
+		case 1:
+			any = append(any, val)
+		case 2:
+			final = val
+		}
+	}
+	return matchesAny(entry, attr, func(v string) bool {
+		v = strings.ToLower(v)
+		if initial != "" && !strings.HasPrefix(v, initial) {
+			return false
+		}
+		if final != "" && !strings.HasSuffix(v, final) {
+			return false
+		}
+		for _, a := range any {
+			idx := strings.Index(v, a)
+			if idx < 0 {
+				return false
+			}
+			v = v[idx+len(a):]
+		}
+		return true
+	})
+}
+
+// matchesAny reports whether any value of entry's attr attribute
+// satisfies pred; a missing attribute never satisfies.
+func matchesAny(entry *Entry, attr string, pred func(string) bool) bool {
+	values, ok := entry.Attributes[attr]
+	if !ok {
+		return false
+	}
+	for _, v := range values {
+		if pred(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalFold compares a value and filter assertion, case-insensitively
+// for DirectoryString-syntax attributes (the default).
+func equalFold(attr, value, assertion string) bool {
+	return strings.EqualFold(value, assertion)
+}
+
+// compare orders value against assertion: numerically for attributes
+// in numericAttributes, lexically and case-insensitively otherwise.
+func compare(attr, value, assertion string) int {
+	if numericAttributes[attr] {
+		v, verr := strconv.ParseInt(value, 10, 64)
+		a, aerr := strconv.ParseInt(assertion, 10, 64)
+		if verr == nil && aerr == nil {
+			switch {
+			case v < a:
+				return -1
+			case v > a:
+				return 1
+			default:
+				return 0
+			}
+		}
+	}
+	return strings.Compare(strings.ToLower(value), strings.ToLower(assertion))
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	ber "gopkg.in/asn1-ber.v1"
+)
+
+func TestApplyFilter(t *testing.T) {
+	entry := &Entry{
+		DN: "cn=alice,ou=users,dc=example,dc=org",
+		Attributes: map[string][]string{
+			"cn":                 {"alice"},
+			"mail":               {"alice@example.org"},
+			"uidNumber":          {"1000"},
+			"objectClass":        {"person", "inetOrgPerson"},
+			"userAccountControl": {"512"},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		want   bool
+	}{
+		{"equality match", "(cn=alice)", true},
+		{"equality mismatch", "(cn=bob)", false},
+		{"equality case-insensitive", "(cn=ALICE)", true},
+		{"present on set attribute", "(mail=*)", true},
+		{"present on missing attribute", "(description=*)", false},
+		{"substrings prefix", "(mail=alice*)", true},
+		{"substrings suffix", "(mail=*example.org)", true},
+		{"substrings any", "(mail=*ice@exam*)", true},
+		{"substrings no match", "(mail=*bob*)", false},
+		{"numeric greater-or-equal true", "(uidNumber>=1000)", true},
+		{"numeric greater-or-equal false", "(uidNumber>=1001)", false},
+		{"numeric less-or-equal true", "(uidNumber<=1000)", true},
+		{"and both true", "(&(cn=alice)(mail=*))", true},
+		{"and one false", "(&(cn=alice)(cn=bob))", false},
+		{"or one true", "(|(cn=bob)(cn=alice))", true},
+		{"or both false", "(|(cn=bob)(cn=carol))", false},
+		{"not true", "(!(cn=bob))", true},
+		{"not false", "(!(cn=alice))", false},
+		{"multi-valued match", "(objectClass=inetOrgPerson)", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			packet, err := CompileFilter(tt.filter)
+			if err != nil {
+				t.Fatalf("CompileFilter(%q): %v", tt.filter, err)
+			}
+			if got := applyFilter(packet, entry); got != tt.want {
+				t.Errorf("applyFilter(%q) = %v, want %v", tt.filter, got, tt.want)
+			}
+
+			// A real client sends the filter over the wire rather than
+			// handing applyFilter the in-process ber.Encode/NewString
+			// packet directly; re-decoding CompileFilter's own bytes
+			// through ber.ReadPacket reproduces that shape (e.g. a
+			// present filter collapses from a constructed packet with
+			// a child into a single IMPLICIT-tagged primitive), which
+			// is what caught the present-filter and substrings
+			// regressions.
+			decoded, err := ber.ReadPacket(bytes.NewReader(packet.Bytes()))
+			if err != nil {
+				t.Fatalf("ReadPacket(CompileFilter(%q).Bytes()): %v", tt.filter, err)
+			}
+			if got := applyFilter(decoded, entry); got != tt.want {
+				t.Errorf("applyFilter(wire-decoded %q) = %v, want %v", tt.filter, got, tt.want)
+			}
+		})
+	}
+}
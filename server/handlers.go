@@ -0,0 +1,341 @@
+package server
+
+import (
+	ber "gopkg.in/asn1-ber.v1"
+
+	"github.com/johnweldon/go-ask-ldap/authn"
+)
+
+// childAt safely returns parent's i'th child, or (nil, false) if parent
+// is nil or i is out of range. Every handler below goes through this
+// (or childString/childInt/decodeAttribute, which build on it) instead
+// of indexing op.Children directly, since op comes straight off the
+// wire and a short or reshaped PDU must not panic the connection.
+func childAt(parent *ber.Packet, i int) (*ber.Packet, bool) {
+	if parent == nil || i < 0 || i >= len(parent.Children) {
+		return nil, false
+	}
+	return parent.Children[i], true
+}
+
+// packetString decodes p as a string. The generic BER decoder only
+// populates .Value for ClassUniversal tags; an IMPLICIT context- or
+// application-tagged primitive (e.g. the bindRequest authentication
+// CHOICE's "simple [0] OCTET STRING", or a delRequest's
+// "[APPLICATION 10] LDAPDN") comes back with .Value still nil and its
+// bytes sitting in .Data instead, so those fall back to the raw
+// content.
+func packetString(p *ber.Packet) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	if s, ok := p.Value.(string); ok {
+		return s, true
+	}
+	if p.ClassType != ber.ClassUniversal && p.Data != nil {
+		return p.Data.String(), true
+	}
+	return "", false
+}
+
+// childString returns parent's i'th child's decoded string value.
+func childString(parent *ber.Packet, i int) (string, bool) {
+	child, ok := childAt(parent, i)
+	if !ok {
+		return "", false
+	}
+	return packetString(child)
+}
+
+// childInt returns parent's i'th child's decoded integer value.
+func childInt(parent *ber.Packet, i int) (int64, bool) {
+	child, ok := childAt(parent, i)
+	if !ok {
+		return 0, false
+	}
+	n, ok := child.Value.(int64)
+	return n, ok
+}
+
+// decodeAttribute decodes a PartialAttribute/AttributeTypeAndValues
+// SEQUENCE { type LDAPString, vals SET OF value } shared by add's
+// AttributeList and modify's per-change attribute, as used by
+// handleAdd and handleModify.
+func decodeAttribute(attr *ber.Packet) (name string, values []string, ok bool) {
+	name, ok = childString(attr, 0)
+	if !ok {
+		return "", nil, false
+	}
+	valuesPacket, ok := childAt(attr, 1)
+	if !ok {
+		return "", nil, false
+	}
+	for _, v := range valuesPacket.Children {
+		value, ok := v.Value.(string)
+		if !ok {
+			return "", nil, false
+		}
+		values = append(values, value)
+	}
+	return name, values, true
+}
+
+func (s *Server) handleBind(sess *session, op *ber.Packet) *ber.Packet {
+	// bindRequest ::= [APPLICATION 0] SEQUENCE { version, name, authentication }
+	name, ok := childString(op, 1)
+	if !ok {
+		return ldapResultPacket(tagBindResponse, resultProtocolError, "malformed bind request")
+	}
+	// authentication CHOICE: [0] simple password, handled only.
+	password, ok := childString(op, 2)
+	if !ok {
+		return ldapResultPacket(tagBindResponse, resultProtocolError, "malformed bind request")
+	}
+
+	if name == "" {
+		sess.boundDN = ""
+		return ldapResultPacket(tagBindResponse, resultSuccess, "")
+	}
+
+	entry, err := s.opts.Backend.Get(DNToKey(name))
+	if err != nil {
+		return ldapResultPacket(tagBindResponse, resultInvalidCredentials, "invalid credentials")
+	}
+	stored := entry.Attributes["userPassword"]
+	if len(stored) == 0 || !verifyUserPassword(stored[0], password) {
+		return ldapResultPacket(tagBindResponse, resultInvalidCredentials, "invalid credentials")
+	}
+
+	sess.boundDN = name
+	return ldapResultPacket(tagBindResponse, resultSuccess, "")
+}
+
+// verifyUserPassword compares a plaintext bind password against a
+// stored userPassword value, which may be a {SSHA}/{BCRYPT} hash or
+// (for test fixtures) cleartext.
+func verifyUserPassword(stored, password string) bool {
+	return authn.VerifyPassword(stored, password)
+}
+
+func (s *Server) handleSearch(sess *session, op *ber.Packet, send func(ber.Tag, *ber.Packet) error) *ber.Packet {
+	base, ok := childString(op, 0)
+	if !ok {
+		return ldapResultPacket(tagSearchDone, resultProtocolError, "malformed search request")
+	}
+	filterPacket, ok := childAt(op, 6)
+	if !ok {
+		return ldapResultPacket(tagSearchDone, resultProtocolError, "malformed search request")
+	}
+	attributes, ok := childAt(op, 7)
+	if !ok {
+		return ldapResultPacket(tagSearchDone, resultProtocolError, "malformed search request")
+	}
+
+	if !allowed(s.opts.ACL, sess.boundDN, RightRead) {
+		return ldapResultPacket(tagSearchDone, resultInsufficientAccess, "insufficient access")
+	}
+
+	entries, err := s.opts.Backend.List(base)
+	if err != nil {
+		return ldapResultPacket(tagSearchDone, resultOperationsError, err.Error())
+	}
+
+	wanted := attributeNames(attributes)
+	for _, entry := range entries {
+		if !applyFilter(filterPacket, entry) {
+			continue
+		}
+		if err := send(tagSearchEntry, searchEntryPacket(entry, wanted)); err != nil {
+			return nil
+		}
+	}
+	return ldapResultPacket(tagSearchDone, resultSuccess, "")
+}
+
+// searchEntryPacket builds a searchResEntry PDU for entry, restricted
+// to wanted attributes (all attributes when wanted is empty).
+func searchEntryPacket(entry *Entry, wanted []string) *ber.Packet {
+	resp := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tagSearchEntry, nil, "SearchResultEntry")
+	resp.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "objectName"))
+
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range entry.Attributes {
+		if !wantsAttribute(wanted, name) {
+			continue
+		}
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "PartialAttribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		vals := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range values {
+			vals.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attr.AppendChild(vals)
+		attrs.AppendChild(attr)
+	}
+	resp.AppendChild(attrs)
+	return resp
+}
+
+func wantsAttribute(wanted []string, name string) bool {
+	if len(wanted) == 0 {
+		return true
+	}
+	for _, w := range wanted {
+		if w == "*" || w == name {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeNames extracts the requested attribute list from a
+// searchRequest's attributes SEQUENCE OF LDAPString; an empty list
+// (or "*") means all attributes.
+func attributeNames(attributes *ber.Packet) []string {
+	var names []string
+	for _, child := range attributes.Children {
+		if name, ok := child.Value.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func (s *Server) handleAdd(sess *session, op *ber.Packet) *ber.Packet {
+	if !allowed(s.opts.ACL, sess.boundDN, RightWrite) {
+		return ldapResultPacket(tagAddResponse, resultInsufficientAccess, "insufficient access")
+	}
+	dn, ok := childString(op, 0)
+	if !ok {
+		return ldapResultPacket(tagAddResponse, resultProtocolError, "malformed add request")
+	}
+	attrList, ok := childAt(op, 1)
+	if !ok {
+		return ldapResultPacket(tagAddResponse, resultProtocolError, "malformed add request")
+	}
+	entry := &Entry{DN: dn, Attributes: map[string][]string{}}
+	for _, attr := range attrList.Children {
+		name, values, ok := decodeAttribute(attr)
+		if !ok {
+			return ldapResultPacket(tagAddResponse, resultProtocolError, "malformed add request")
+		}
+		entry.Attributes[name] = values
+	}
+	if err := s.opts.Backend.Put(DNToKey(dn), entry); err != nil {
+		return ldapResultPacket(tagAddResponse, resultOperationsError, err.Error())
+	}
+	return ldapResultPacket(tagAddResponse, resultSuccess, "")
+}
+
+func (s *Server) handleModify(sess *session, op *ber.Packet) *ber.Packet {
+	if !allowed(s.opts.ACL, sess.boundDN, RightWrite) {
+		return ldapResultPacket(tagModifyResponse, resultInsufficientAccess, "insufficient access")
+	}
+	dn, ok := childString(op, 0)
+	if !ok {
+		return ldapResultPacket(tagModifyResponse, resultProtocolError, "malformed modify request")
+	}
+	changes, ok := childAt(op, 1)
+	if !ok {
+		return ldapResultPacket(tagModifyResponse, resultProtocolError, "malformed modify request")
+	}
+	key := DNToKey(dn)
+	entry, err := s.opts.Backend.Get(key)
+	if err != nil {
+		return ldapResultPacket(tagModifyResponse, resultNoSuchObject, "no such object")
+	}
+
+	for _, change := range changes.Children {
+		changeOp, ok := childInt(change, 0)
+		if !ok {
+			return ldapResultPacket(tagModifyResponse, resultProtocolError, "malformed modify request")
+		}
+		attr, ok := childAt(change, 1)
+		if !ok {
+			return ldapResultPacket(tagModifyResponse, resultProtocolError, "malformed modify request")
+		}
+		name, values, ok := decodeAttribute(attr)
+		if !ok {
+			return ldapResultPacket(tagModifyResponse, resultProtocolError, "malformed modify request")
+		}
+		switch changeOp {
+		case 0: // add
+			entry.Attributes[name] = append(entry.Attributes[name], values...)
+		case 1: // delete
+			if len(values) == 0 {
+				delete(entry.Attributes, name)
+			} else {
+				entry.Attributes[name] = removeValues(entry.Attributes[name], values)
+			}
+		case 2: // replace
+			entry.Attributes[name] = values
+		}
+	}
+
+	if err := s.opts.Backend.Put(key, entry); err != nil {
+		return ldapResultPacket(tagModifyResponse, resultOperationsError, err.Error())
+	}
+	return ldapResultPacket(tagModifyResponse, resultSuccess, "")
+}
+
+func removeValues(values, remove []string) []string {
+	var kept []string
+	for _, v := range values {
+		drop := false
+		for _, r := range remove {
+			if v == r {
+				drop = true
+				break
+			}
+		}
+		if !drop {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+func (s *Server) handleDelete(sess *session, op *ber.Packet) *ber.Packet {
+	if !allowed(s.opts.ACL, sess.boundDN, RightWrite) {
+		return ldapResultPacket(tagDelResponse, resultInsufficientAccess, "insufficient access")
+	}
+	dn, ok := packetString(op)
+	if !ok {
+		return ldapResultPacket(tagDelResponse, resultProtocolError, "malformed delete request")
+	}
+	if err := s.opts.Backend.Delete(DNToKey(dn)); err != nil {
+		return ldapResultPacket(tagDelResponse, resultNoSuchObject, "no such object")
+	}
+	return ldapResultPacket(tagDelResponse, resultSuccess, "")
+}
+
+func (s *Server) handleCompare(sess *session, op *ber.Packet) *ber.Packet {
+	if !allowed(s.opts.ACL, sess.boundDN, RightRead) {
+		return ldapResultPacket(tagCompareResponse, resultInsufficientAccess, "insufficient access")
+	}
+	dn, ok := childString(op, 0)
+	if !ok {
+		return ldapResultPacket(tagCompareResponse, resultProtocolError, "malformed compare request")
+	}
+	ava, ok := childAt(op, 1)
+	if !ok {
+		return ldapResultPacket(tagCompareResponse, resultProtocolError, "malformed compare request")
+	}
+	attr, ok := childString(ava, 0)
+	if !ok {
+		return ldapResultPacket(tagCompareResponse, resultProtocolError, "malformed compare request")
+	}
+	assertion, ok := childString(ava, 1)
+	if !ok {
+		return ldapResultPacket(tagCompareResponse, resultProtocolError, "malformed compare request")
+	}
+
+	entry, err := s.opts.Backend.Get(DNToKey(dn))
+	if err != nil {
+		return ldapResultPacket(tagCompareResponse, resultNoSuchObject, "no such object")
+	}
+	if matchesAny(entry, attr, func(v string) bool { return equalFold(attr, v, assertion) }) {
+		return ldapResultPacket(tagCompareResponse, resultCompareTrue, "")
+	}
+	return ldapResultPacket(tagCompareResponse, resultCompareFalse, "")
+}
@@ -0,0 +1,28 @@
+package server
+
+import (
+	"bytes"
+	"testing"
+
+	ber "gopkg.in/asn1-ber.v1"
+)
+
+// TestPacketStringContextTagged guards against regressing to a
+// Value-only decode: a context- or application-tagged primitive (as
+// used by the bindRequest authentication CHOICE and delRequest) is
+// never populated in .Value by the BER decoder, only in .Data.
+func TestPacketStringContextTagged(t *testing.T) {
+	contextTagged := &ber.Packet{Identifier: ber.Identifier{ClassType: ber.ClassContext}, Data: bytes.NewBufferString("hunter2")}
+	if got, ok := packetString(contextTagged); !ok || got != "hunter2" {
+		t.Errorf("packetString(context-tagged) = (%q, %v), want (\"hunter2\", true)", got, ok)
+	}
+
+	universal := ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "alice", "cn")
+	if got, ok := packetString(universal); !ok || got != "alice" {
+		t.Errorf("packetString(universal) = (%q, %v), want (\"alice\", true)", got, ok)
+	}
+
+	if _, ok := packetString(nil); ok {
+		t.Errorf("packetString(nil) = ok, want !ok")
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// MemoryBackend is an in-memory Backend, useful for tests and for
+// serving small, ephemeral directories without an external dependency.
+type MemoryBackend struct {
+	mu    sync.RWMutex
+	store map[string][]byte
+}
+
+// NewMemoryBackend returns an empty MemoryBackend.
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{store: map[string][]byte{}}
+}
+
+func (m *MemoryBackend) Get(key string) (*Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	raw, ok := m.store[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	var entry Entry
+	if err := json.Unmarshal(raw, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func (m *MemoryBackend) Put(key string, entry *Entry) error {
+	raw, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store[key] = raw
+	return nil
+}
+
+func (m *MemoryBackend) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.store[key]; !ok {
+		return ErrNotFound
+	}
+	delete(m.store, key)
+	return nil
+}
+
+func (m *MemoryBackend) List(base string) ([]*Entry, error) {
+	prefix := DNToKey(base)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var entries []*Entry
+	for key, raw := range m.store {
+		if key != prefix && !strings.HasPrefix(key, prefix+"/") {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(raw, &entry); err != nil {
+			return nil, err
+		}
+		entries = append(entries, &entry)
+	}
+	return entries, nil
+}
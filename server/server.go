@@ -0,0 +1,186 @@
+// Package server implements an LDAP frontend (bind/search/add/modify/
+// delete/compare/unbind) over a pluggable Backend, in the spirit of the
+// bottin LDAP-over-KV design.
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+
+	ber "gopkg.in/asn1-ber.v1"
+)
+
+// LDAP protocol op application tags, as used on the wire inside a
+// LDAPMessage's protocolOp CHOICE.
+const (
+	tagBindRequest     = 0
+	tagBindResponse    = 1
+	tagUnbindRequest   = 2
+	tagSearchRequest   = 3
+	tagSearchEntry     = 4
+	tagSearchDone      = 5
+	tagModifyRequest   = 6
+	tagModifyResponse  = 7
+	tagAddRequest      = 8
+	tagAddResponse     = 9
+	tagDelRequest      = 10
+	tagDelResponse     = 11
+	tagCompareRequest  = 14
+	tagCompareResponse = 15
+)
+
+// resultCodes mirror the subset of RFC 4511 result codes this server emits.
+const (
+	resultSuccess            = 0
+	resultOperationsError    = 1
+	resultProtocolError      = 2
+	resultCompareFalse       = 5
+	resultCompareTrue        = 6
+	resultNoSuchObject       = 32
+	resultInvalidCredentials = 49
+	resultInsufficientAccess = 50
+)
+
+// Options configures a Server.
+type Options struct {
+	Suffix     string
+	Backend    Backend
+	ACL        []ACLEntry
+	BindSecure bool
+	TLSConfig  *tls.Config
+}
+
+// Server is the LDAP frontend: it accepts connections, decodes
+// LDAPMessage PDUs, and dispatches each protocolOp to the matching
+// handler against Options.Backend.
+type Server struct {
+	opts Options
+}
+
+// New returns a Server configured with opts. It performs no I/O.
+func New(opts Options) *Server {
+	return &Server{opts: opts}
+}
+
+// ListenAndServe listens on addr and serves connections until the
+// listener fails or the process exits.
+func (s *Server) ListenAndServe(addr string) error {
+	var ln net.Listener
+	var err error
+	if s.opts.BindSecure {
+		ln, err = tls.Listen("tcp", addr, s.opts.TLSConfig)
+	} else {
+		ln, err = net.Listen("tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("INFO:: listening on %s (suffix=%s backend=%T)\n", addr, s.opts.Suffix, s.opts.Backend)
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// session holds the per-connection bind state used by ACL checks.
+type session struct {
+	boundDN string
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer conn.Close()
+	sess := &session{}
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+		if len(packet.Children) < 2 {
+			return
+		}
+		messageID, ok := packet.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+		op := packet.Children[1]
+
+		send := func(responseTag ber.Tag, response *ber.Packet) error {
+			envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAPMessage")
+			envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+			envelope.AppendChild(response)
+			_, err := conn.Write(envelope.Bytes())
+			return err
+		}
+
+		response, done := s.safeDispatch(sess, op, send, messageID)
+		if response != nil {
+			if err := send(ber.Tag(op.Tag), response); err != nil {
+				return
+			}
+		}
+		if done {
+			return
+		}
+	}
+}
+
+// safeDispatch runs dispatch under a recover(), so a single malformed
+// PDU that slips past a handler's own validation (a type assertion or
+// slice index the handler didn't guard) panics only this message's
+// processing, not the goroutine — and therefore not the listener or
+// any other client's session. A recovered panic closes this
+// connection; it has no way to know which response tag the caller
+// expected.
+func (s *Server) safeDispatch(sess *session, op *ber.Packet, send func(ber.Tag, *ber.Packet) error, messageID int64) (response *ber.Packet, done bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("ERROR:: recovered from panic handling message %d: %v\n", messageID, r)
+			response, done = nil, true
+		}
+	}()
+	return s.dispatch(sess, op, send)
+}
+
+// dispatch routes a single protocolOp to its handler, returning the
+// (possibly nil) final response packet to write back and whether the
+// connection should close afterward (set by unbindRequest). Handlers
+// that emit intermediate PDUs (searchResEntry) use send directly and
+// return only the terminal searchResDone/etc through the result value.
+func (s *Server) dispatch(sess *session, op *ber.Packet, send func(ber.Tag, *ber.Packet) error) (*ber.Packet, bool) {
+	switch ber.Tag(op.Tag) {
+	case tagBindRequest:
+		return s.handleBind(sess, op), false
+	case tagUnbindRequest:
+		return nil, true
+	case tagSearchRequest:
+		return s.handleSearch(sess, op, send), false
+	case tagAddRequest:
+		return s.handleAdd(sess, op), false
+	case tagModifyRequest:
+		return s.handleModify(sess, op), false
+	case tagDelRequest:
+		return s.handleDelete(sess, op), false
+	case tagCompareRequest:
+		return s.handleCompare(sess, op), false
+	default:
+		return ldapResultPacket(tagSearchDone, resultOperationsError, fmt.Sprintf("unsupported op %d", op.Tag)), false
+	}
+}
+
+// ldapResultPacket builds the common resultCode/matchedDN/diagnosticMessage
+// response envelope shared by every LDAP response PDU except
+// searchResEntry (searchResDone included).
+func ldapResultPacket(responseTag ber.Tag, resultCode int, diagnostic string) *ber.Packet {
+	resp := ber.Encode(ber.ClassApplication, ber.TypeConstructed, responseTag, nil, "Response")
+	resp.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(resultCode), "resultCode"))
+	resp.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	resp.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, diagnostic, "diagnosticMessage"))
+	return resp
+}